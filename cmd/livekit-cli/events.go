@@ -0,0 +1,101 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// eventSink writes newline-delimited JSON events with a stable schema
+// ({ts, type, room, ...}) to a file, stdout, or a Unix socket, turning
+// join-room into a scriptable probe for CI/soak tests.
+type eventSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	room   string
+}
+
+// newEventSink opens the destination named by --events-out. path may be
+// "-" for stdout, the path of a Unix socket to dial, or a file path to
+// create. An empty path disables the sink; emit is a no-op on a nil sink.
+func newEventSink(path string, room string) (*eventSink, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if path == "-" {
+		return &eventSink{w: os.Stdout, room: room}, nil
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		return &eventSink{w: conn, closer: conn, room: room}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &eventSink{w: f, closer: f, room: room}, nil
+}
+
+func (s *eventSink) emit(eventType string, fields map[string]interface{}) {
+	if s == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"ts":   time.Now().UnixMilli(),
+		"type": eventType,
+		"room": s.room,
+	}
+	for k, v := range fields {
+		event[k] = v
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Errorw("could not marshal event", err, "type", eventType)
+		return
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err = s.w.Write(data); err != nil {
+		logger.Errorw("could not write event", err, "type", eventType)
+	}
+}
+
+func (s *eventSink) setRoom(room string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.room = room
+	s.mu.Unlock()
+}
+
+func (s *eventSink) Close() {
+	if s == nil || s.closer == nil {
+		return
+	}
+	_ = s.closer.Close()
+}
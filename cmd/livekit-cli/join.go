@@ -15,6 +15,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net"
@@ -22,9 +23,9 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
-	"bytes"
 
 	"github.com/pion/webrtc/v3"
 	"github.com/urfave/cli/v2"
@@ -53,12 +54,46 @@ var (
 					Name: "publish",
 					Usage: "files to publish as tracks to room (supports .h264, .ivf, .ogg). " +
 						"can be used multiple times to publish multiple files. " +
-						"can publish from Unix or TCP socket using the format `codec://socket_name` or `codec://host:address` respectively. Valid codecs are h264, vp8, opus",
+						"can publish from Unix or TCP socket using the format `codec://socket_name` or `codec://host:address` respectively. Valid codecs are h264, vp8, opus. " +
+						"can also publish rtmp://, rtsp://, or mp4://path sources, which are transcoded via GStreamer",
 				},
 				&cli.Float64Flag{
 					Name:  "fps",
 					Usage: "if video files are published, indicates FPS of video",
 				},
+				&cli.BoolFlag{
+					Name:  "record",
+					Usage: "record all subscribed tracks to disk",
+				},
+				&cli.StringFlag{
+					Name:  "record-dir",
+					Usage: "directory to write recorded tracks to",
+					Value: ".",
+				},
+				&cli.StringFlag{
+					Name:  "record-participants",
+					Usage: "comma-separated list of participant identities to record; defaults to everyone",
+				},
+				&cli.StringFlag{
+					Name:  "subscribe-quality",
+					Usage: "simulcast layer to request for subscribed video tracks: high, medium, low, or auto",
+					Value: subscribeQualityHigh,
+				},
+				&cli.StringFlag{
+					Name:  "events-out",
+					Usage: "emit every room/publish event as newline-delimited JSON to a file, `-` for stdout, or a Unix socket path",
+				},
+			),
+		},
+		{
+			Name:     "whip-ingest",
+			Usage:    "Starts a WHIP endpoint that forwards ingested WebRTC tracks into a room",
+			Action:   whipIngest,
+			Category: "Simulate",
+			Flags: withDefaultFlags(
+				roomFlag,
+				identityFlag,
+				whipListenFlag,
 			),
 		},
 	}
@@ -73,6 +108,19 @@ func joinRoom(c *cli.Context) error {
 		return err
 	}
 
+	var rec *recorder
+	if c.Bool("record") {
+		rec = newRecorder(c.String("record-dir"), c.String("record-participants"))
+	}
+
+	qc := newQualityController(c.String("subscribe-quality"))
+
+	es, err := newEventSink(c.String("events-out"), c.String("room"))
+	if err != nil {
+		return err
+	}
+	defer es.Close()
+
 	roomCB := &lksdk.RoomCallback{
 		ParticipantCallback: lksdk.ParticipantCallback{
 			OnDataReceived: func(data []byte, rp *lksdk.RemoteParticipant) {
@@ -81,9 +129,12 @@ func joinRoom(c *cli.Context) error {
 					identity = rp.Identity()
 				}
 				logger.Infow("received data", "data", data, "participant", identity)
+				es.emit("data_received", map[string]interface{}{"participant": identity, "size": len(data)})
 			},
 			OnConnectionQualityChanged: func(update *livekit.ConnectionQualityInfo, p lksdk.Participant) {
 				logger.Debugw("connection quality changed", "participant", p.Identity(), "quality", update.Quality)
+				qc.onConnectionQualityChanged(update, p)
+				es.emit("connection_quality_changed", map[string]interface{}{"participant": p.Identity(), "quality": update.Quality.String()})
 			},
 			OnTrackSubscribed: func(track *webrtc.TrackRemote, pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
 				logger.Infow("track subscribed",
@@ -92,6 +143,16 @@ func joinRoom(c *cli.Context) error {
 					"source", pub.Source(),
 					"participant", participant.Identity(),
 				)
+				if rec != nil {
+					rec.onTrackSubscribed(track, pub, participant)
+				}
+				qc.onTrackSubscribed(pub, participant)
+				es.emit("track_subscribed", map[string]interface{}{
+					"participant": participant.Identity(),
+					"trackID":     pub.SID(),
+					"kind":        pub.Kind().String(),
+					"source":      pub.Source().String(),
+				})
 			},
 			OnTrackUnsubscribed: func(track *webrtc.TrackRemote, pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
 				logger.Infow("track unsubscribed",
@@ -100,6 +161,14 @@ func joinRoom(c *cli.Context) error {
 					"source", pub.Source(),
 					"participant", participant.Identity(),
 				)
+				if rec != nil {
+					rec.onTrackUnsubscribed(pub)
+				}
+				qc.onTrackUnsubscribed(pub)
+				es.emit("track_unsubscribed", map[string]interface{}{
+					"participant": participant.Identity(),
+					"trackID":     pub.SID(),
+				})
 			},
 			OnTrackUnpublished: func(pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
 				logger.Infow("track unpublished",
@@ -108,6 +177,10 @@ func joinRoom(c *cli.Context) error {
 					"source", pub.Source(),
 					"participant", participant.Identity(),
 				)
+				es.emit("track_unpublished", map[string]interface{}{
+					"participant": participant.Identity(),
+					"trackID":     pub.SID(),
+				})
 			},
 			OnTrackMuted: func(pub lksdk.TrackPublication, participant lksdk.Participant) {
 				logger.Infow("track muted",
@@ -116,6 +189,10 @@ func joinRoom(c *cli.Context) error {
 					"source", pub.Source(),
 					"participant", participant.Identity(),
 				)
+				es.emit("track_muted", map[string]interface{}{
+					"participant": participant.Identity(),
+					"trackID":     pub.SID(),
+				})
 			},
 			OnTrackUnmuted: func(pub lksdk.TrackPublication, participant lksdk.Participant) {
 				logger.Infow("track unmuted",
@@ -124,19 +201,27 @@ func joinRoom(c *cli.Context) error {
 					"source", pub.Source(),
 					"participant", participant.Identity(),
 				)
+				es.emit("track_unmuted", map[string]interface{}{
+					"participant": participant.Identity(),
+					"trackID":     pub.SID(),
+				})
 			},
 		},
 		OnRoomMetadataChanged: func(metadata string) {
 			logger.Infow("room metadata changed", "metadata", metadata)
+			es.emit("room_metadata_changed", map[string]interface{}{"metadata": metadata})
 		},
 		OnReconnecting: func() {
 			logger.Infow("reconnecting to room")
+			es.emit("reconnecting", nil)
 		},
 		OnReconnected: func() {
 			logger.Infow("reconnected to room")
+			es.emit("reconnected", nil)
 		},
 		OnDisconnected: func() {
 			logger.Infow("disconnected from room")
+			es.emit("disconnected", nil)
 		},
 	}
 
@@ -166,6 +251,8 @@ func joinRoom(c *cli.Context) error {
 
 
 	logger.Infow("connected to room", "room", room.Name())
+	es.setRoom(room.Name())
+	es.emit("connected", nil)
 
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -179,27 +266,34 @@ func joinRoom(c *cli.Context) error {
 	if c.StringSlice("publish") != nil {
 		fps := c.Float64("fps")
 		for _, pub := range c.StringSlice("publish") {
-			if err = handlePublish(room, pub, fps); err != nil {
+			if err = handlePublish(room, pub, fps, es); err != nil {
 				return err
 			}
 		}
 	}
 
 	<-done
+	if rec != nil {
+		rec.Close()
+	}
 	return nil
 }
 
-func handlePublish(room *lksdk.Room, name string, fps float64) error {
+func handlePublish(room *lksdk.Room, name string, fps float64, es *eventSink) error {
+	// See if we're dealing with an RTMP/RTSP/file URL that needs transcoding
+	if isGstFormat(name) {
+		return publishGst(room, name, es)
+	}
 	// See if we're dealing with a socket
 	if isSocketFormat(name) {
 		mimeType, socketType, address, err := parseSocketFromName(name)
 		if err != nil {
 			return err
 		}
-		return publishSocket(room, mimeType, socketType, address, fps)
+		return publishSocket(room, mimeType, socketType, address, fps, es)
 	}
 	// Else, handle file
-	return publishFile(room, name, fps)
+	return publishFile(room, name, fps, es)
 }
 
 func publishDemo(room *lksdk.Room) error {
@@ -228,17 +322,19 @@ func publishDemo(room *lksdk.Room) error {
 	return err
 }
 
-func publishFile(room *lksdk.Room, filename string, fps float64) error {
+func publishFile(room *lksdk.Room, filename string, fps float64, es *eventSink) error {
 	// Configure provider
 	var pub *lksdk.LocalTrackPublication
 	opts := []lksdk.ReaderSampleProviderOption{
 		lksdk.ReaderTrackWithOnWriteComplete(func() {
 			fmt.Println("finished writing file", filename)
+			es.emit("publish_stop", map[string]interface{}{"file": filename})
 			if pub != nil {
 				_ = room.LocalParticipant.UnpublishTrack(pub.SID())
 			}
 		}),
 	}
+	es.emit("publish_start", map[string]interface{}{"file": filename})
 
 	// Set frame rate if it's a video stream and FPS is set
 	ext := filepath.Ext(filename)
@@ -294,7 +390,56 @@ func isSocketFormat(name string) bool {
 	return strings.Contains(name, mimeDelimiter)
 }
 
-func publishSocket(room *lksdk.Room, mimeType string, socketType string, address string, fps float64) error {
+var gstSchemes = []string{"rtmp://", "rtsp://", "mp4://"}
+
+func isGstFormat(name string) bool {
+	for _, scheme := range gstSchemes {
+		if strings.HasPrefix(name, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func publishGst(room *lksdk.Room, url string, es *eventSink) error {
+	gstPub, err := provider2.NewGstPublisher(url)
+	if err != nil {
+		return err
+	}
+	es.emit("publish_start", map[string]interface{}{"url": url})
+
+	// Either track stopping (e.g. the pipeline hits EOS) means the stream is
+	// done; only emit publish_stop once regardless of which track notices.
+	var stopOnce sync.Once
+	onComplete := func() {
+		stopOnce.Do(func() {
+			es.emit("publish_stop", map[string]interface{}{"url": url})
+		})
+	}
+
+	videoTrack, err := lksdk.NewLocalSampleTrack(gstPub.VideoCodec())
+	if err != nil {
+		return err
+	}
+	if err = videoTrack.StartWrite(gstPub.VideoProvider(), onComplete); err != nil {
+		return err
+	}
+	if _, err = room.LocalParticipant.PublishTrack(videoTrack, &lksdk.TrackPublicationOptions{Name: url}); err != nil {
+		return err
+	}
+
+	audioTrack, err := lksdk.NewLocalSampleTrack(gstPub.AudioCodec())
+	if err != nil {
+		return err
+	}
+	if err = audioTrack.StartWrite(gstPub.AudioProvider(), onComplete); err != nil {
+		return err
+	}
+	_, err = room.LocalParticipant.PublishTrack(audioTrack, &lksdk.TrackPublicationOptions{Name: url})
+	return err
+}
+
+func publishSocket(room *lksdk.Room, mimeType string, socketType string, address string, fps float64, es *eventSink) error {
 	var mime string
 	switch {
 	case strings.Contains(mimeType, "data"):
@@ -314,18 +459,20 @@ func publishSocket(room *lksdk.Room, mimeType string, socketType string, address
 	if err != nil {
 		return err
 	}
+	es.emit("socket_connected", map[string]interface{}{"address": address, "mime": mime})
 
 	// Publish to room
-	err = publishReader(room, sock, mime, fps)
+	err = publishReader(room, sock, mime, fps, es)
 	return err
 }
 
-func publishReader(room *lksdk.Room, in io.ReadCloser, mime string, fps float64) error {
+func publishReader(room *lksdk.Room, in io.ReadCloser, mime string, fps float64, es *eventSink) error {
 	// Configure provider
 	var pub *lksdk.LocalTrackPublication
 	opts := []lksdk.ReaderSampleProviderOption{
 		lksdk.ReaderTrackWithOnWriteComplete(func() {
 			fmt.Printf("finished writing %s stream\n", mime)
+			es.emit("socket_eof", map[string]interface{}{"mime": mime})
 			if pub != nil {
 				_ = room.LocalParticipant.UnpublishTrack(pub.SID())
 			}
@@ -0,0 +1,146 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livekit/protocol/livekit"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+const (
+	subscribeQualityHigh   = "high"
+	subscribeQualityMedium = "medium"
+	subscribeQualityLow    = "low"
+	subscribeQualityAuto   = "auto"
+)
+
+const excellentHoldBeforeStepUp = 10 * time.Second
+
+// videoTrackState tracks the simulcast layer currently requested for a
+// single subscribed video publication.
+type videoTrackState struct {
+	pub                 *lksdk.RemoteTrackPublication
+	participantIdentity string
+	layer               livekit.VideoQuality
+}
+
+// qualityController applies --subscribe-quality to subscribed video
+// tracks. In "auto" mode it steps the requested simulcast layer down on
+// OnConnectionQualityChanged(POOR), and up after a participant has held
+// EXCELLENT for more than 10s.
+type qualityController struct {
+	mode string
+
+	mu             sync.Mutex
+	tracks         map[string]*videoTrackState // trackSID -> state
+	excellentSince map[string]time.Time        // participant identity -> since
+}
+
+func newQualityController(mode string) *qualityController {
+	return &qualityController{
+		mode:           mode,
+		tracks:         make(map[string]*videoTrackState),
+		excellentSince: make(map[string]time.Time),
+	}
+}
+
+func (q *qualityController) onTrackSubscribed(pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
+	if pub.Kind() != lksdk.TrackKindVideo {
+		return
+	}
+
+	layer := livekit.VideoQuality_HIGH
+	switch q.mode {
+	case subscribeQualityMedium:
+		layer = livekit.VideoQuality_MEDIUM
+	case subscribeQualityLow:
+		layer = livekit.VideoQuality_LOW
+	case subscribeQualityAuto:
+		layer = livekit.VideoQuality_MEDIUM
+	}
+	pub.SetVideoQuality(layer)
+
+	q.mu.Lock()
+	q.tracks[pub.SID()] = &videoTrackState{
+		pub:                 pub,
+		participantIdentity: participant.Identity(),
+		layer:               layer,
+	}
+	q.mu.Unlock()
+}
+
+func (q *qualityController) onTrackUnsubscribed(pub *lksdk.RemoteTrackPublication) {
+	q.mu.Lock()
+	delete(q.tracks, pub.SID())
+	q.mu.Unlock()
+}
+
+func (q *qualityController) onConnectionQualityChanged(update *livekit.ConnectionQualityInfo, participant lksdk.Participant) {
+	if q.mode != subscribeQualityAuto {
+		return
+	}
+
+	identity := participant.Identity()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	switch update.Quality {
+	case livekit.ConnectionQuality_POOR:
+		delete(q.excellentSince, identity)
+		q.stepLocked(identity, -1)
+	case livekit.ConnectionQuality_EXCELLENT:
+		since, ok := q.excellentSince[identity]
+		if !ok {
+			q.excellentSince[identity] = time.Now()
+			return
+		}
+		if time.Since(since) > excellentHoldBeforeStepUp {
+			q.stepLocked(identity, 1)
+			q.excellentSince[identity] = time.Now()
+		}
+	default:
+		delete(q.excellentSince, identity)
+	}
+}
+
+// stepLocked adjusts every video track belonging to identity by delta
+// layers, clamped to [LOW, HIGH]. q.mu must be held.
+func (q *qualityController) stepLocked(identity string, delta int) {
+	for _, state := range q.tracks {
+		if state.participantIdentity != identity {
+			continue
+		}
+		newLayer := clampQuality(int(state.layer) + delta)
+		if newLayer == state.layer {
+			continue
+		}
+		state.layer = newLayer
+		state.pub.SetVideoQuality(newLayer)
+	}
+}
+
+func clampQuality(layer int) livekit.VideoQuality {
+	if layer < int(livekit.VideoQuality_LOW) {
+		return livekit.VideoQuality_LOW
+	}
+	if layer > int(livekit.VideoQuality_HIGH) {
+		return livekit.VideoQuality_HIGH
+	}
+	return livekit.VideoQuality(layer)
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media/h264writer"
+	"github.com/pion/webrtc/v3/pkg/media/ivfwriter"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+
+	"github.com/livekit/protocol/logger"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+// mediaWriter is the common subset of the pion media writer packages
+// (oggwriter, ivfwriter, h264writer) we record tracks with.
+type mediaWriter interface {
+	WriteRTP(pkt *rtp.Packet) error
+	Close() error
+}
+
+// recorder subscribes to remote tracks and writes each one to disk,
+// keyed by participant identity and track SID.
+type recorder struct {
+	dir          string
+	participants map[string]bool // empty means record everyone
+
+	mu      sync.Mutex
+	writers map[string]mediaWriter
+}
+
+func newRecorder(dir string, participantsCSV string) *recorder {
+	r := &recorder{dir: dir, writers: make(map[string]mediaWriter)}
+	if participantsCSV != "" {
+		r.participants = make(map[string]bool)
+		for _, p := range strings.Split(participantsCSV, ",") {
+			r.participants[strings.TrimSpace(p)] = true
+		}
+	}
+	return r
+}
+
+func (r *recorder) shouldRecord(identity string) bool {
+	return len(r.participants) == 0 || r.participants[identity]
+}
+
+func (r *recorder) onTrackSubscribed(track *webrtc.TrackRemote, pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
+	if !r.shouldRecord(participant.Identity()) {
+		return
+	}
+
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		logger.Errorw("could not create record dir", err, "dir", r.dir)
+		return
+	}
+
+	ext := extForMime(track.Codec().MimeType)
+	if ext == "" {
+		logger.Infow("no recorder available for codec, skipping", "mime", track.Codec().MimeType)
+		return
+	}
+
+	filename := filepath.Join(r.dir, fmt.Sprintf("%s_%s.%s", participant.Identity(), pub.SID(), ext))
+	writer, err := newMediaWriter(filename, track.Codec())
+	if err != nil {
+		logger.Errorw("could not create media writer", err, "file", filename)
+		return
+	}
+
+	logger.Infow("recording track", "file", filename, "participant", participant.Identity())
+
+	r.mu.Lock()
+	r.writers[pub.SID()] = writer
+	r.mu.Unlock()
+
+	go r.writeTrack(track, writer, pub.SID())
+}
+
+// writeTrack feeds RTP packets straight from the track to writer. The pion
+// media writers (h264writer, ivfwriter, oggwriter) depacketize internally,
+// so no samplebuilder is needed in front of them.
+func (r *recorder) writeTrack(track *webrtc.TrackRemote, writer mediaWriter, trackSID string) {
+	for {
+		pkt, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+		if err = writer.WriteRTP(pkt); err != nil {
+			logger.Errorw("could not write recorded packet", err, "trackSID", trackSID)
+			return
+		}
+	}
+}
+
+func (r *recorder) onTrackUnsubscribed(pub *lksdk.RemoteTrackPublication) {
+	r.mu.Lock()
+	writer, ok := r.writers[pub.SID()]
+	delete(r.writers, pub.SID())
+	r.mu.Unlock()
+
+	if ok {
+		_ = writer.Close()
+	}
+}
+
+// Close finalizes every file still being recorded, e.g. on SIGINT.
+func (r *recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for sid, writer := range r.writers {
+		_ = writer.Close()
+		delete(r.writers, sid)
+	}
+}
+
+func extForMime(mimeType string) string {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypeH264):
+		return "h264"
+	case strings.EqualFold(mimeType, webrtc.MimeTypeVP8), strings.EqualFold(mimeType, webrtc.MimeTypeVP9):
+		return "ivf"
+	case strings.EqualFold(mimeType, webrtc.MimeTypeOpus):
+		return "ogg"
+	default:
+		return ""
+	}
+}
+
+func newMediaWriter(filename string, codec webrtc.RTPCodecParameters) (mediaWriter, error) {
+	switch {
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeH264):
+		return h264writer.New(filename), nil
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeVP8), strings.EqualFold(codec.MimeType, webrtc.MimeTypeVP9):
+		return ivfwriter.New(filename)
+	case strings.EqualFold(codec.MimeType, webrtc.MimeTypeOpus):
+		return oggwriter.New(filename, codec.ClockRate, codec.Channels)
+	default:
+		return nil, fmt.Errorf("unsupported codec for recording: %s", codec.MimeType)
+	}
+}
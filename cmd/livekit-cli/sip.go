@@ -0,0 +1,113 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/livekit/livekit-cli/pkg/sip"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+var (
+	sipURIFlag = &cli.StringFlag{
+		Name:     "sip-uri",
+		Usage:    "destination sip-uri to dial, e.g. sip:1000@example.com",
+		Required: true,
+	}
+	sipFromFlag = &cli.StringFlag{
+		Name:     "from",
+		Usage:    "caller name, used as the SDP origin username and in the published track name; it is not set as the INVITE's From header",
+		Required: true,
+	}
+	sipAuthUserFlag = &cli.StringFlag{
+		Name:  "auth-user",
+		Usage: "username for SIP digest authentication; accepted but not used: no digest challenge is answered, so a 401/407 response ends the call",
+	}
+	sipAuthPassFlag = &cli.StringFlag{
+		Name:  "auth-pass",
+		Usage: "password for SIP digest authentication; accepted but not used: no digest challenge is answered, so a 401/407 response ends the call",
+	}
+)
+
+var SipCommands = []*cli.Command{
+	{
+		Name:     "sip",
+		Usage:    "Place and bridge outbound SIP calls",
+		Category: "Simulate",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "dial",
+				Usage:  "Places an outbound SIP call and bridges its audio into a room",
+				Action: sipDial,
+				Flags: withDefaultFlags(
+					sipURIFlag,
+					sipFromFlag,
+					sipAuthUserFlag,
+					sipAuthPassFlag,
+					roomFlag,
+					identityFlag,
+				),
+			},
+		},
+	},
+}
+
+func sipDial(c *cli.Context) error {
+	pc, err := loadProjectDetails(c)
+	if err != nil {
+		return err
+	}
+
+	dialer, err := sip.NewDialer()
+	if err != nil {
+		return err
+	}
+
+	room, err := lksdk.ConnectToRoom(pc.URL, lksdk.ConnectInfo{
+		APIKey:              pc.APIKey,
+		APISecret:           pc.APISecret,
+		RoomName:            c.String("room"),
+		ParticipantIdentity: c.String("identity"),
+	}, dialer.RoomCallback())
+	if err != nil {
+		return err
+	}
+	defer room.Disconnect()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	return dialer.Dial(ctx, sip.DialOptions{
+		URI:      c.String("sip-uri"),
+		From:     c.String("from"),
+		AuthUser: c.String("auth-user"),
+		AuthPass: c.String("auth-pass"),
+		Room:     room,
+		Identity: c.String("identity"),
+	})
+}
@@ -0,0 +1,249 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/urfave/cli/v2"
+
+	"github.com/livekit/protocol/logger"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+var whipListenFlag = &cli.StringFlag{
+	Name:  "whip-listen",
+	Usage: "address to listen on for WHIP ingest requests",
+	Value: ":8585",
+}
+
+// whipServer implements the WebRTC-HTTP Ingestion Protocol (WHIP) and
+// republishes every ingested track into a LiveKit room.
+type whipServer struct {
+	room *lksdk.Room
+
+	mu       sync.Mutex
+	sessions map[string]*whipSession
+}
+
+// whipSession tracks the resources created for a single WHIP publisher so
+// that a DELETE on its resource URL can tear everything down.
+type whipSession struct {
+	pc   *webrtc.PeerConnection
+	pubs []*lksdk.LocalTrackPublication
+}
+
+func whipIngest(c *cli.Context) error {
+	pc, err := loadProjectDetails(c)
+	if err != nil {
+		return err
+	}
+
+	room, err := lksdk.ConnectToRoom(pc.URL, lksdk.ConnectInfo{
+		APIKey:              pc.APIKey,
+		APISecret:           pc.APISecret,
+		RoomName:            c.String("room"),
+		ParticipantIdentity: c.String("identity"),
+	}, &lksdk.RoomCallback{})
+	if err != nil {
+		return err
+	}
+	defer room.Disconnect()
+
+	ws := &whipServer{
+		room:     room,
+		sessions: make(map[string]*whipSession),
+	}
+
+	addr := c.String("whip-listen")
+	logger.Infow("starting WHIP endpoint", "address", addr, "room", room.Name())
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/whip", ws.handleWHIP)
+	mux.HandleFunc("/whip/resource/", ws.handleResource)
+
+	return http.ListenAndServe(addr, mux)
+}
+
+func (ws *whipServer) handleWHIP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		// Answers with the allowed methods only; no ICE servers are
+		// advertised (no Link headers are set).
+		w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPost:
+		ws.handlePublish(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ws *whipServer) handlePublish(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != "application/sdp" {
+		http.Error(w, "expected Content-Type: application/sdp", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offerSDP, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sess, answer, err := ws.startSession(string(offerSDP))
+	if err != nil {
+		logger.Errorw("failed to start WHIP session", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resourceID := randomID()
+	ws.mu.Lock()
+	ws.sessions[resourceID] = sess
+	ws.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", "/whip/resource/"+resourceID)
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer))
+}
+
+func (ws *whipServer) handleResource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resourceID := r.URL.Path[len("/whip/resource/"):]
+
+	ws.mu.Lock()
+	sess, ok := ws.sessions[resourceID]
+	delete(ws.sessions, resourceID)
+	ws.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "unknown resource", http.StatusNotFound)
+		return
+	}
+
+	ws.teardown(sess)
+	w.WriteHeader(http.StatusOK)
+}
+
+// startSession negotiates a recvonly PeerConnection for the given offer and
+// republishes any tracks it receives into the room. It returns once ICE
+// gathering has completed so a non-trickle answer can be returned.
+func (ws *whipServer) startSession(offerSDP string) (*whipSession, string, error) {
+	mediaEngine := &webrtc.MediaEngine{}
+	if err := mediaEngine.RegisterDefaultCodecs(); err != nil {
+		return nil, "", err
+	}
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(mediaEngine))
+
+	peerConn, err := api.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sess := &whipSession{pc: peerConn}
+
+	peerConn.OnTrack(func(remote *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+		if err := ws.forwardTrack(sess, remote); err != nil {
+			logger.Errorw("failed to republish WHIP track", err, "kind", remote.Kind().String())
+		}
+	})
+
+	// SetRemoteDescription auto-creates a recvonly transceiver for every
+	// m-line in the offer, which is what makes OnTrack fire below; adding
+	// transceivers here would append extra m-lines the offer never had.
+	offer := webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offerSDP}
+	if err = peerConn.SetRemoteDescription(offer); err != nil {
+		_ = peerConn.Close()
+		return nil, "", err
+	}
+
+	answer, err := peerConn.CreateAnswer(nil)
+	if err != nil {
+		_ = peerConn.Close()
+		return nil, "", err
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(peerConn)
+	if err = peerConn.SetLocalDescription(answer); err != nil {
+		_ = peerConn.Close()
+		return nil, "", err
+	}
+	<-gatherComplete
+
+	return sess, peerConn.LocalDescription().SDP, nil
+}
+
+func (ws *whipServer) forwardTrack(sess *whipSession, remote *webrtc.TrackRemote) error {
+	// Forward raw RTP rather than re-sampling: a LocalSampleTrack only
+	// accepts media.Sample writes, so a pass-through track that exposes
+	// WriteRTP is what's needed here.
+	local, err := webrtc.NewTrackLocalStaticRTP(remote.Codec().RTPCodecCapability, remote.ID(), remote.StreamID())
+	if err != nil {
+		return err
+	}
+
+	pub, err := ws.room.LocalParticipant.PublishTrack(local, &lksdk.TrackPublicationOptions{
+		Name: remote.ID(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ws.mu.Lock()
+	sess.pubs = append(sess.pubs, pub)
+	ws.mu.Unlock()
+
+	go func() {
+		for {
+			pkt, _, err := remote.ReadRTP()
+			if err != nil {
+				return
+			}
+			if err = local.WriteRTP(pkt); err != nil {
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (ws *whipServer) teardown(sess *whipSession) {
+	ws.mu.Lock()
+	pubs := sess.pubs
+	ws.mu.Unlock()
+
+	for _, pub := range pubs {
+		_ = ws.room.LocalParticipant.UnpublishTrack(pub.SID())
+	}
+	_ = sess.pc.Close()
+}
+
+func randomID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
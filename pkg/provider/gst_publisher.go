@@ -0,0 +1,52 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package provider
+
+import "strings"
+
+// gstPublisherParams and its With* options are shared between the gst and
+// non-gst builds of GstPublisher so callers don't need to build-tag their
+// own call sites.
+type gstPublisherParams struct {
+	width, height int
+	bitrate       uint
+}
+
+type GstPublisherOption func(*gstPublisherParams)
+
+// WithGstResolution sets the output resolution video is encoded at.
+func WithGstResolution(width, height int) GstPublisherOption {
+	return func(p *gstPublisherParams) {
+		p.width = width
+		p.height = height
+	}
+}
+
+// WithGstBitrate sets the target video bitrate, in kbps.
+func WithGstBitrate(bitrate uint) GstPublisherOption {
+	return func(p *gstPublisherParams) {
+		p.bitrate = bitrate
+	}
+}
+
+// normalizeURI rewrites our custom mp4:// scheme (used to disambiguate a
+// plain file path from an RTMP/RTSP stream) to the file:// scheme that
+// uridecodebin expects.
+func normalizeURI(url string) string {
+	if strings.HasPrefix(url, "mp4://") {
+		return "file://" + strings.TrimPrefix(url, "mp4://")
+	}
+	return url
+}
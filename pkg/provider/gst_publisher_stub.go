@@ -0,0 +1,68 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !gst
+
+package provider
+
+import (
+	"fmt"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// GstPublisher is a stub used when the CLI is built without the gst tag, so
+// the default build stays free of the cgo/GStreamer dependency. Build with
+// -tags gst to get a working implementation.
+type GstPublisher struct{}
+
+func NewGstPublisher(url string, opts ...GstPublisherOption) (*GstPublisher, error) {
+	return nil, fmt.Errorf("gstreamer support not compiled in; rebuild with -tags gst")
+}
+
+func (p *GstPublisher) VideoCodec() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{}
+}
+
+func (p *GstPublisher) AudioCodec() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{}
+}
+
+func (p *GstPublisher) VideoProvider() *gstSampleProvider {
+	return &gstSampleProvider{}
+}
+
+func (p *GstPublisher) AudioProvider() *gstSampleProvider {
+	return &gstSampleProvider{}
+}
+
+func (p *GstPublisher) Close() error {
+	return nil
+}
+
+// gstSampleProvider is a stub lksdk.SampleProvider for the non-gst build.
+type gstSampleProvider struct{}
+
+func (g *gstSampleProvider) NextSample() (media.Sample, error) {
+	return media.Sample{}, fmt.Errorf("gstreamer support not compiled in; rebuild with -tags gst")
+}
+
+func (g *gstSampleProvider) OnBind() error {
+	return nil
+}
+
+func (g *gstSampleProvider) OnUnbind() error {
+	return nil
+}
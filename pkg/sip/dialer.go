@@ -0,0 +1,304 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sip places outbound SIP calls and bridges the RTP audio into a
+// LiveKit room, paralleling livekit/sip but usable directly from the CLI
+// for testing and one-off dialing.
+package sip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/emiago/sipgo"
+	"github.com/emiago/sipgo/sip"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"gopkg.in/hraban/opus.v2"
+
+	"github.com/livekit/protocol/livekit"
+	"github.com/livekit/protocol/logger"
+	lksdk "github.com/livekit/server-sdk-go"
+)
+
+const (
+	sampleRate     = 8000
+	roomSampleRate = 48000
+	dtmfPayload    = 101
+)
+
+// DialOptions configures an outbound call placed via Dial.
+type DialOptions struct {
+	URI      string
+	From     string
+	AuthUser string
+	AuthPass string
+	Room     *lksdk.Room
+	Identity string
+}
+
+// DTMFEvent is the JSON payload published over the room's data channel
+// whenever an RFC 2833 DTMF event is received from the SIP leg. Its schema
+// is stable: {"type":"dtmf","digit":"5"}.
+type DTMFEvent struct {
+	Type  string `json:"type"`
+	Digit string `json:"digit"`
+}
+
+var dtmfDigits = "0123456789*#ABCD"
+
+// Dialer places outbound SIP calls and bridges the resulting RTP audio
+// into a LiveKit room as an Opus track, translating RFC 2833 DTMF events
+// into room data messages. It also bridges audio the other way: tracks the
+// room subscribes it to are decoded and re-encoded as G.711 back to the
+// SIP leg.
+type Dialer struct {
+	client *sipgo.Client
+
+	mu          sync.Mutex
+	rtpConn     *rtpSession
+	payloadType byte
+}
+
+// NewDialer creates a Dialer backed by a fresh SIP user agent.
+func NewDialer() (*Dialer, error) {
+	ua, err := sipgo.NewUA()
+	if err != nil {
+		return nil, fmt.Errorf("could not create SIP user agent: %w", err)
+	}
+	client, err := sipgo.NewClient(ua)
+	if err != nil {
+		return nil, fmt.Errorf("could not create SIP client: %w", err)
+	}
+	return &Dialer{client: client}, nil
+}
+
+// RoomCallback returns the lksdk.RoomCallback to connect the room with so
+// that tracks it subscribes to get bridged back into the active SIP leg.
+func (d *Dialer) RoomCallback() *lksdk.RoomCallback {
+	return &lksdk.RoomCallback{
+		ParticipantCallback: lksdk.ParticipantCallback{
+			OnTrackSubscribed: func(track *webrtc.TrackRemote, pub *lksdk.RemoteTrackPublication, participant *lksdk.RemoteParticipant) {
+				if track.Kind() != webrtc.RTPCodecTypeAudio {
+					return
+				}
+				go d.bridgeRoomTrack(track)
+			},
+		},
+	}
+}
+
+// bridgeRoomTrack decodes a subscribed room Opus track and re-encodes it
+// as G.711 toward the current SIP leg, i.e. the room-to-SIP half of the
+// bridge (the SIP-to-room half is g711Provider, consumed by the published
+// track started in Dial).
+func (d *Dialer) bridgeRoomTrack(track *webrtc.TrackRemote) {
+	decoder, err := opus.NewDecoder(roomSampleRate, 1)
+	if err != nil {
+		logger.Errorw("could not create opus decoder", err)
+		return
+	}
+
+	pcm := make([]int16, roomSampleRate/50) // 20ms of 48kHz audio
+	var seq uint16
+	for {
+		pkt, _, err := track.ReadRTP()
+		if err != nil {
+			return
+		}
+
+		d.mu.Lock()
+		conn := d.rtpConn
+		payloadType := d.payloadType
+		d.mu.Unlock()
+		if conn == nil {
+			continue
+		}
+
+		n, err := decoder.Decode(pkt.Payload, pcm)
+		if err != nil {
+			logger.Errorw("could not decode room opus packet", err)
+			continue
+		}
+
+		// The SIP leg is narrowband G.711 (8kHz); naively decimate the
+		// 48kHz decode down to 8kHz rather than pulling in a resampler.
+		narrowband := downsampleTo8k(pcm[:n])
+
+		var encoded []byte
+		if payloadType == payloadTypePCMA {
+			encoded = encodeAlaw(narrowband)
+		} else {
+			encoded = encodeMulaw(narrowband)
+		}
+
+		seq++
+		out := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				PayloadType:    payloadType,
+				SequenceNumber: seq,
+				Timestamp:      pkt.Timestamp,
+				SSRC:           pkt.SSRC,
+			},
+			Payload: encoded,
+		}
+		if err = conn.WritePacket(out); err != nil {
+			return
+		}
+	}
+}
+
+func downsampleTo8k(pcm []int16) []int16 {
+	const ratio = roomSampleRate / sampleRate
+	out := make([]int16, len(pcm)/ratio)
+	for i := range out {
+		out[i] = pcm[i*ratio]
+	}
+	return out
+}
+
+// Dial sends an INVITE to opts.URI, negotiates G.711 audio, and bridges
+// the call into opts.Room until ctx is canceled, then sends a BYE. A hangup
+// from the remote side is not detected and does not end Dial.
+func (d *Dialer) Dial(ctx context.Context, opts DialOptions) error {
+	rtpConn, err := newRTPSession()
+	if err != nil {
+		return err
+	}
+	defer rtpConn.Close()
+
+	req, err := d.buildInvite(opts, rtpConn.LocalPort())
+	if err != nil {
+		return err
+	}
+
+	tx, err := d.client.TransactionRequest(req)
+	if err != nil {
+		return fmt.Errorf("could not send INVITE: %w", err)
+	}
+	defer tx.Terminate()
+
+	resp, err := waitForFinalResponse(tx)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != sip.StatusOK {
+		return fmt.Errorf("call to %s failed: %d %s", opts.URI, resp.StatusCode, resp.Reason)
+	}
+
+	// The INVITE client transaction only auto-ACKs non-2xx responses; per
+	// RFC 3261 §13.2.2.4 we must generate the ACK for a 2xx ourselves.
+	if err = d.client.WriteRequest(sip.NewAckRequest(req, resp, nil)); err != nil {
+		return fmt.Errorf("could not send ACK: %w", err)
+	}
+
+	remoteAddr, payloadType, err := parseAnswerSDP(resp.Body())
+	if err != nil {
+		return err
+	}
+	rtpConn.SetRemote(remoteAddr)
+
+	d.mu.Lock()
+	d.rtpConn = rtpConn
+	d.payloadType = payloadType
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		d.rtpConn = nil
+		d.mu.Unlock()
+	}()
+
+	logger.Infow("SIP call established", "uri", opts.URI, "room", opts.Room.Name())
+
+	track, err := lksdk.NewLocalSampleTrack(opus2OpusCapability())
+	if err != nil {
+		return err
+	}
+	encoder, err := opus.NewEncoder(sampleRate, 1, opus.AppVoIP)
+	if err != nil {
+		return err
+	}
+	if err = track.StartWrite(&g711Provider{conn: rtpConn, payloadType: payloadType, encoder: encoder}, nil); err != nil {
+		return err
+	}
+	if _, err = opts.Room.LocalParticipant.PublishTrack(track, &lksdk.TrackPublicationOptions{Name: "sip-" + opts.From}); err != nil {
+		return err
+	}
+
+	// RFC 2833 senders repeat a keypress's packet every ~20ms for as long as
+	// it's held, marking only the trailing packet(s) as end; only publish
+	// once per keypress, not once per retransmitted end packet.
+	var lastDigit byte
+	var lastTimestamp uint32
+	haveLast := false
+	go rtpConn.run(func(event *dtmfEvent) {
+		if event == nil || !event.end {
+			return
+		}
+		if haveLast && event.digit == lastDigit && event.timestamp == lastTimestamp {
+			return
+		}
+		lastDigit, lastTimestamp, haveLast = event.digit, event.timestamp, true
+		d.publishDTMF(opts.Room, event)
+	})
+
+	<-ctx.Done()
+	return d.hangup(req, resp)
+}
+
+func (d *Dialer) publishDTMF(room *lksdk.Room, event *dtmfEvent) {
+	if int(event.digit) >= len(dtmfDigits) {
+		return
+	}
+	payload, err := json.Marshal(DTMFEvent{Type: "dtmf", Digit: string(dtmfDigits[event.digit])})
+	if err != nil {
+		logger.Errorw("could not marshal DTMF event", err)
+		return
+	}
+	if err = room.LocalParticipant.PublishData(payload, livekit.DataPacket_RELIABLE, nil); err != nil {
+		logger.Errorw("could not publish DTMF event", err)
+	}
+}
+
+// hangup sends a BYE for the dialog established by invite/resp, targeting
+// the remote Contact (not the original Request-URI) and carrying the
+// matching Call-ID/From/To tags, per the dialog the 200 OK response set up.
+func (d *Dialer) hangup(invite *sip.Request, resp *sip.Response) error {
+	recipient := invite.Recipient
+	if contact := resp.Contact(); contact != nil {
+		recipient = contact.Address
+	}
+
+	bye := sip.NewRequest(sip.BYE, recipient)
+	if callID := resp.CallID(); callID != nil {
+		bye.AppendHeader(callID)
+	}
+	if from := resp.From(); from != nil {
+		bye.AppendHeader(from)
+	}
+	if to := resp.To(); to != nil {
+		bye.AppendHeader(to)
+	}
+
+	tx, err := d.client.TransactionRequest(bye)
+	if err != nil {
+		return err
+	}
+	defer tx.Terminate()
+	_, err = waitForFinalResponse(tx)
+	return err
+}
@@ -0,0 +1,129 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+// decodeMulaw converts G.711 mu-law samples to 16-bit linear PCM.
+func decodeMulaw(payload []byte) []int16 {
+	out := make([]int16, len(payload))
+	for i, b := range payload {
+		b = ^b
+		sign := b & 0x80
+		exponent := (b >> 4) & 0x07
+		mantissa := b & 0x0f
+		sample := int16(mantissa)<<3 + 0x84
+		sample <<= exponent
+		sample -= 0x84
+		if sign != 0 {
+			sample = -sample
+		}
+		out[i] = sample
+	}
+	return out
+}
+
+// decodeAlaw converts G.711 a-law samples to 16-bit linear PCM.
+func decodeAlaw(payload []byte) []int16 {
+	out := make([]int16, len(payload))
+	for i, b := range payload {
+		b ^= 0x55
+		sign := b & 0x80
+		exponent := (b >> 4) & 0x07
+		mantissa := b & 0x0f
+		sample := int16(mantissa) << 4
+		sample += 8
+		if exponent != 0 {
+			sample += 0x100
+			sample <<= exponent - 1
+		}
+		if sign == 0 {
+			sample = -sample
+		}
+		out[i] = sample
+	}
+	return out
+}
+
+// The segment-search encode tables and BIAS/CLIP constants below follow the
+// standard ITU-T/Sun reference implementation of G.711.
+var segAlawEnd = [8]int16{0x1F, 0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF}
+var segUlawEnd = [8]int16{0x3F, 0x7F, 0xFF, 0x1FF, 0x3FF, 0x7FF, 0xFFF, 0x1FFF}
+
+const (
+	ulawBias = 0x84
+	ulawClip = 8159
+)
+
+func segmentSearch(val int16, table [8]int16) int16 {
+	for i, bound := range table {
+		if val <= bound {
+			return int16(i)
+		}
+	}
+	return int16(len(table))
+}
+
+// encodeAlaw converts 16-bit linear PCM to G.711 a-law samples.
+func encodeAlaw(pcm []int16) []byte {
+	out := make([]byte, len(pcm))
+	for i, sample := range pcm {
+		sample >>= 3
+		mask := int16(0xD5)
+		if sample < 0 {
+			mask = 0x55
+			sample = -sample - 1
+		}
+		seg := segmentSearch(sample, segAlawEnd)
+		var aval byte
+		if seg >= 8 {
+			aval = 0x7F
+		} else {
+			aval = byte(seg) << 4
+			if seg < 2 {
+				aval |= byte(sample>>1) & 0x0f
+			} else {
+				aval |= byte(sample>>uint(seg)) & 0x0f
+			}
+		}
+		out[i] = aval ^ byte(mask)
+	}
+	return out
+}
+
+// encodeMulaw converts 16-bit linear PCM to G.711 mu-law samples.
+func encodeMulaw(pcm []int16) []byte {
+	out := make([]byte, len(pcm))
+	for i, sample := range pcm {
+		sample >>= 2
+		mask := int16(0xFF)
+		if sample < 0 {
+			sample = -sample
+			mask = 0x7F
+		}
+		if sample > ulawClip {
+			sample = ulawClip
+		}
+		sample += ulawBias >> 2
+
+		seg := segmentSearch(sample, segUlawEnd)
+		var uval byte
+		if seg >= 8 {
+			uval = 0x7F
+		} else {
+			uval = byte(seg)<<4 | byte(sample>>uint(seg+1))&0x0f
+		}
+		out[i] = uval ^ byte(mask)
+	}
+	return out
+}
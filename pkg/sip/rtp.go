@@ -0,0 +1,167 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"gopkg.in/hraban/opus.v2"
+
+	"github.com/livekit/protocol/logger"
+)
+
+// dtmfEvent is a decoded RFC 2833 telephone-event payload. A single keypress
+// is carried by many packets sharing one timestamp (the base of the event),
+// with only the trailing packets marked end; timestamp lets a consumer tell
+// a repeated end packet for the same keypress apart from a new one.
+type dtmfEvent struct {
+	digit     byte
+	end       bool
+	timestamp uint32
+}
+
+// rtpSession owns the UDP socket used for the SIP leg's audio. A single
+// goroutine (started by run) reads the socket and demuxes by payload type:
+// audio packets are handed to audioCh for g711Provider.NextSample to
+// consume, DTMF packets go straight to the onDTMF callback. Nothing else
+// may read from conn, or packets get randomly stolen from one consumer or
+// the other.
+type rtpSession struct {
+	conn    *net.UDPConn
+	remote  *net.UDPAddr
+	audioCh chan *rtp.Packet
+}
+
+func newRTPSession() (*rtpSession, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: 0})
+	if err != nil {
+		return nil, err
+	}
+	return &rtpSession{conn: conn, audioCh: make(chan *rtp.Packet, 50)}, nil
+}
+
+func (s *rtpSession) LocalPort() int {
+	return s.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (s *rtpSession) SetRemote(addr *net.UDPAddr) {
+	s.remote = addr
+}
+
+func (s *rtpSession) Close() error {
+	return s.conn.Close()
+}
+
+func (s *rtpSession) WritePacket(pkt *rtp.Packet) error {
+	if s.remote == nil {
+		return nil
+	}
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.WriteToUDP(buf, s.remote)
+	return err
+}
+
+// run is the session's single RTP reader. It demuxes every inbound packet
+// by payload type, handing DTMF events to onDTMF and pushing everything
+// else onto audioCh, until the socket is closed.
+func (s *rtpSession) run(onDTMF func(event *dtmfEvent)) {
+	defer close(s.audioCh)
+	for {
+		buf := make([]byte, 1500)
+		n, _, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		pkt := &rtp.Packet{}
+		if err = pkt.Unmarshal(buf[:n]); err != nil {
+			continue
+		}
+		if pkt.PayloadType == dtmfPayload {
+			if onDTMF != nil {
+				if event := decodeDTMFEvent(pkt.Payload); event != nil {
+					event.timestamp = pkt.Timestamp
+					onDTMF(event)
+				}
+			}
+			continue
+		}
+		select {
+		case s.audioCh <- pkt:
+		default:
+			logger.Debugw("dropping SIP audio packet, consumer too slow")
+		}
+	}
+}
+
+// decodeDTMFEvent parses an RFC 2833 telephone-event payload.
+func decodeDTMFEvent(payload []byte) *dtmfEvent {
+	if len(payload) < 4 {
+		return nil
+	}
+	return &dtmfEvent{
+		digit: payload[0],
+		end:   payload[1]&0x80 != 0,
+	}
+}
+
+func opus2OpusCapability() webrtc.RTPCodecCapability {
+	return webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 1}
+}
+
+// g711Provider adapts inbound G.711 RTP audio from the SIP leg into Opus
+// samples for publishing as an lksdk.LocalSampleTrack.
+type g711Provider struct {
+	conn        *rtpSession
+	payloadType byte
+	encoder     *opus.Encoder
+}
+
+func (p *g711Provider) NextSample() (media.Sample, error) {
+	pkt, ok := <-p.conn.audioCh
+	if !ok {
+		return media.Sample{}, io.EOF
+	}
+
+	var pcm []int16
+	if p.payloadType == payloadTypePCMA {
+		pcm = decodeAlaw(pkt.Payload)
+	} else {
+		pcm = decodeMulaw(pkt.Payload)
+	}
+
+	out := make([]byte, 4000)
+	n, err := p.encoder.Encode(pcm, out)
+	if err != nil {
+		return media.Sample{}, err
+	}
+	return media.Sample{Data: out[:n], Duration: 20 * time.Millisecond}, nil
+}
+
+func (p *g711Provider) OnBind() error {
+	return nil
+}
+
+func (p *g711Provider) OnUnbind() error {
+	logger.Infow("SIP audio track unbound")
+	return nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 LiveKit, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sip
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emiago/sipgo/sip"
+)
+
+const (
+	payloadTypePCMU = 0
+	payloadTypePCMA = 8
+)
+
+// buildInvite constructs the outbound INVITE, offering G.711 (both
+// mu-law and a-law) plus the RFC 2833 telephone-event payload.
+func (d *Dialer) buildInvite(opts DialOptions, localRTPPort int) (*sip.Request, error) {
+	recipient, err := sip.ParseUri(opts.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sip-uri %q: %w", opts.URI, err)
+	}
+
+	localIP, err := localOutboundIP()
+	if err != nil {
+		return nil, err
+	}
+
+	sdp := fmt.Sprintf(
+		"v=0\r\n"+
+			"o=%s 0 0 IN IP4 %s\r\n"+
+			"s=livekit-cli\r\n"+
+			"c=IN IP4 %s\r\n"+
+			"t=0 0\r\n"+
+			"m=audio %d RTP/AVP %d %d %d\r\n"+
+			"a=rtpmap:%d PCMU/8000\r\n"+
+			"a=rtpmap:%d PCMA/8000\r\n"+
+			"a=rtpmap:%d telephone-event/8000\r\n"+
+			"a=sendrecv\r\n",
+		opts.From, localIP, localIP, localRTPPort,
+		payloadTypePCMU, payloadTypePCMA, dtmfPayload,
+		payloadTypePCMU, payloadTypePCMA, dtmfPayload,
+	)
+
+	req := sip.NewRequest(sip.INVITE, recipient)
+	req.AppendHeader(sip.NewHeader("Content-Type", "application/sdp"))
+	req.SetBody([]byte(sdp))
+	return req, nil
+}
+
+// parseAnswerSDP extracts the remote RTP address and the negotiated G.711
+// payload type (preferring PCMA if both were offered and accepted) from an
+// INVITE's 200 OK answer.
+func parseAnswerSDP(body []byte) (*net.UDPAddr, byte, error) {
+	var ip string
+	var port int
+	payloadType := byte(payloadTypePCMU)
+
+	for _, line := range strings.Split(string(body), "\r\n") {
+		switch {
+		case strings.HasPrefix(line, "c=IN IP4 "):
+			ip = strings.TrimPrefix(line, "c=IN IP4 ")
+		case strings.HasPrefix(line, "m=audio "):
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			p, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid media port in answer: %w", err)
+			}
+			port = p
+			for _, pt := range fields[3:] {
+				if pt == strconv.Itoa(payloadTypePCMA) {
+					payloadType = payloadTypePCMA
+				}
+			}
+		}
+	}
+
+	if ip == "" || port == 0 {
+		return nil, 0, fmt.Errorf("could not find audio media in SDP answer")
+	}
+
+	return &net.UDPAddr{IP: net.ParseIP(ip), Port: port}, payloadType, nil
+}
+
+func waitForFinalResponse(tx sip.ClientTransaction) (*sip.Response, error) {
+	for {
+		select {
+		case res := <-tx.Responses():
+			if res.StatusCode >= 200 {
+				return res, nil
+			}
+		case <-time.After(30 * time.Second):
+			return nil, fmt.Errorf("timed out waiting for SIP response")
+		}
+	}
+}
+
+func localOutboundIP() (string, error) {
+	conn, err := net.Dial("udp", "8.8.8.8:80")
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}